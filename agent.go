@@ -0,0 +1,29 @@
+package main
+
+import "context"
+
+// Agent fetches the most recent (or currently playing) track for a user
+// from a particular scrobbling backend. Implementations normalize their
+// provider's response shape into the common track struct so the rest of
+// the server stays provider-agnostic.
+type Agent interface {
+	// Name returns the registry key for this agent, e.g. "lastfm".
+	Name() string
+	// GetNowPlaying returns the user's most recent track, or nil if they
+	// have no scrobbles.
+	GetNowPlaying(ctx context.Context, user string) (*track, error)
+}
+
+var agentRegistry = map[string]Agent{}
+
+// RegisterAgent adds an agent to the registry under its own Name(). Agents
+// register themselves from an init() in their own file.
+func RegisterAgent(a Agent) {
+	agentRegistry[a.Name()] = a
+}
+
+// getAgent looks up a registered agent by name.
+func getAgent(name string) (Agent, bool) {
+	a, ok := agentRegistry[name]
+	return a, ok
+}
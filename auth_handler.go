@@ -0,0 +1,135 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const pendingTokenTTL = 10 * time.Minute
+
+// authFlow tracks Last.fm desktop-auth tokens this server has issued but
+// not yet exchanged for a session, keyed by token. Its only job is to
+// prove a /auth/lastfm/callback request corresponds to a token this server
+// actually handed out - it does NOT bind the flow to any caller-asserted
+// user, since nothing proves the caller of /start is entitled to claim
+// that identity. The session is instead saved under the Last.fm username
+// returned by auth.getSession: the only "user" a completed flow can ever
+// prove is the account that approved it on Last.fm.
+type authFlow struct {
+	mu      sync.Mutex
+	pending map[string]time.Time
+}
+
+func newAuthFlow() *authFlow {
+	return &authFlow{pending: make(map[string]time.Time)}
+}
+
+func (f *authFlow) start(token string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.pending[token] = time.Now()
+}
+
+// resolve reports whether token was issued by this server and hasn't
+// expired, removing it so it can't be replayed.
+func (f *authFlow) resolve(token string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	issuedAt, found := f.pending[token]
+	delete(f.pending, token)
+	return found && time.Since(issuedAt) <= pendingTokenTTL
+}
+
+// authLastFmStartHandler serves GET /auth/lastfm/start: it requests a
+// token from Last.fm and redirects the browser to approve it. The identity
+// that ends up authenticated is decided entirely by whoever approves on
+// Last.fm, not by any parameter here.
+func (s *Server) authLastFmStartHandler(w http.ResponseWriter, r *http.Request) {
+	lastfm, ok := s.lastFmAgent()
+	if !ok || s.cfg.LastFmSharedSecret == "" {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"message": "LASTFM_AUTH_DISABLED"})
+		return
+	}
+
+	token, err := lastfm.GetToken(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusBadGateway, map[string]string{"message": "UPSTREAM_ERROR"})
+		return
+	}
+
+	s.authFlow.start(token)
+	http.Redirect(w, r, lastfm.AuthURL(token), http.StatusFound)
+}
+
+// authLastFmCallbackHandler serves GET /auth/lastfm/callback?token=...:
+// once the token has been approved on Last.fm, it exchanges it for a
+// session key and persists it under the approving Last.fm username - the
+// only identity a completed flow can prove - for future scrobble/love
+// calls to /{lastfm_username}/...
+func (s *Server) authLastFmCallbackHandler(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"message": "BAD_REQUEST"})
+		return
+	}
+
+	if ok := s.authFlow.resolve(token); !ok {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"message": "UNKNOWN_OR_EXPIRED_TOKEN"})
+		return
+	}
+
+	if s.store == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"message": "HISTORY_DISABLED"})
+		return
+	}
+
+	lastfm, ok := s.lastFmAgent()
+	if !ok || s.cfg.LastFmSharedSecret == "" {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"message": "LASTFM_AUTH_DISABLED"})
+		return
+	}
+
+	sessionKey, lastfmUsername, err := lastfm.GetSession(r.Context(), token)
+	if err != nil {
+		writeJSON(w, http.StatusBadGateway, map[string]string{"message": "UPSTREAM_ERROR"})
+		return
+	}
+
+	apiToken, apiTokenHash, err := newAPIToken()
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"message": "INTERNAL_ERROR"})
+		return
+	}
+
+	if err := s.store.SaveLastFmSession(lastfmUsername, sessionKey, lastfmUsername, apiTokenHash); err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"message": "STORE_ERROR"})
+		return
+	}
+
+	// apiToken is only ever returned here - the store keeps just its hash -
+	// so the caller must save it now to authenticate future
+	// scrobble/love/unlove requests as "Authorization: Bearer <api_token>".
+	writeJSON(w, http.StatusOK, map[string]string{
+		"message":         "AUTHENTICATED",
+		"lastfm_username": lastfmUsername,
+		"api_token":       apiToken,
+	})
+}
+
+// newAPIToken generates a random per-user write token and returns it
+// alongside the sha256 hex digest that gets persisted in its place.
+func newAPIToken() (token, hash string, err error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", err
+	}
+	token = base64.RawURLEncoding.EncodeToString(raw)
+	sum := sha256.Sum256([]byte(token))
+	return token, hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	defaultProviderName = "lastfm"
+	defaultInfoCacheTTL = 24 * time.Hour
+	defaultMaxStale     = 5 * time.Minute
+	defaultSwrThreshold = 1 * time.Second
+)
+
+// Config holds environment-derived settings for the server.
+type Config struct {
+	LastFmAPIKey       string
+	DefaultProvider    string
+	UserProviders      map[string]string
+	DBPath             string
+	InfoCacheTTL       time.Duration
+	LastFmSharedSecret string
+	MaxStale           time.Duration
+	SwrThreshold       time.Duration
+}
+
+// loadConfig reads server configuration from the environment.
+func loadConfig() Config {
+	cfg := Config{
+		LastFmAPIKey:       os.Getenv("LASTFM_API_KEY"),
+		DefaultProvider:    os.Getenv("DEFAULT_PROVIDER"),
+		UserProviders:      parseUserProviders(os.Getenv("USER_PROVIDERS")),
+		DBPath:             os.Getenv("DB_PATH"),
+		InfoCacheTTL:       defaultInfoCacheTTL,
+		LastFmSharedSecret: os.Getenv("LASTFM_SHARED_SECRET"),
+		MaxStale:           defaultMaxStale,
+		SwrThreshold:       defaultSwrThreshold,
+	}
+	if cfg.DefaultProvider == "" {
+		cfg.DefaultProvider = defaultProviderName
+	}
+	if ttl, ok := parseDurationEnv("INFO_CACHE_TTL"); ok {
+		cfg.InfoCacheTTL = ttl
+	}
+	if ttl, ok := parseDurationEnv("MAX_STALE"); ok {
+		cfg.MaxStale = ttl
+	}
+	if ttl, ok := parseDurationEnv("SWR_THRESHOLD"); ok {
+		cfg.SwrThreshold = ttl
+	}
+	return cfg
+}
+
+// lastFmRequired reports whether Last.fm is actually in play for this
+// deployment - as the server-wide default provider or as any user's
+// override - so a ListenBrainz-only deployment doesn't need a
+// LASTFM_API_KEY to start.
+func (c Config) lastFmRequired() bool {
+	if c.DefaultProvider == "lastfm" {
+		return true
+	}
+	for _, provider := range c.UserProviders {
+		if provider == "lastfm" {
+			return true
+		}
+	}
+	return false
+}
+
+// parseDurationEnv reads and parses an environment variable as a
+// time.Duration, returning ok=false if it's unset or malformed.
+func parseDurationEnv(name string) (time.Duration, bool) {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return 0, false
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil {
+		return 0, false
+	}
+	return d, true
+}
+
+// parseUserProviders parses a comma-separated "user:provider" list, e.g.
+// "alice:listenbrainz,bob:lastfm", into a per-user provider override map.
+func parseUserProviders(raw string) map[string]string {
+	m := make(map[string]string)
+	if raw == "" {
+		return m
+	}
+	for _, pair := range strings.Split(raw, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		parts := strings.SplitN(pair, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		m[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+	return m
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+)
+
+const defaultHistoryLimit = 50
+
+// historyHandler serves GET /{user}/history?limit=N&since=UTS, returning the
+// user's persisted listen history as JSON or, with ?format=rss, as an RSS
+// 2.0 feed.
+func (s *Server) historyHandler(w http.ResponseWriter, r *http.Request, user string) {
+	if s.store == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"message": "HISTORY_DISABLED"})
+		return
+	}
+
+	limit := defaultHistoryLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		if n, err := strconv.Atoi(raw); err == nil && n > 0 {
+			limit = n
+		}
+	}
+
+	var since int64
+	if raw := r.URL.Query().Get("since"); raw != "" {
+		if n, err := strconv.ParseInt(raw, 10, 64); err == nil {
+			since = n
+		}
+	}
+
+	listens, err := s.store.RecentListens(user, limit, since)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"message": "HISTORY_ERROR"})
+		return
+	}
+
+	if r.URL.Query().Get("format") == "rss" {
+		writeRSS(w, user, listens)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string][]Listen{"history": listens})
+}
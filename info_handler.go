@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"net/http"
+)
+
+// lastFmAgent returns the registered Last.fm agent for enrichment lookups.
+// Album/artist metadata is only available from Last.fm regardless of which
+// provider is serving now-playing data.
+func (s *Server) lastFmAgent() (*LastFmAgent, bool) {
+	a, ok := getAgent(defaultProviderName)
+	if !ok {
+		return nil, false
+	}
+	lf, ok := a.(*LastFmAgent)
+	return lf, ok
+}
+
+func (s *Server) getAlbumInfo(ctx context.Context, lastfm *LastFmAgent, artistName, albumName, mbid string) (*albumInfo, error) {
+	key := "album:" + mbid
+	if mbid == "" {
+		key = "album:" + artistName + ":" + albumName
+	}
+
+	if cached, ok := s.cache.GetInfo(key); ok {
+		return cached.(*albumInfo), nil
+	}
+
+	info, err := lastfm.GetAlbumInfo(ctx, artistName, albumName, mbid)
+	if err != nil {
+		return nil, err
+	}
+	s.cache.SetInfo(key, info)
+	return info, nil
+}
+
+func (s *Server) getArtistInfo(ctx context.Context, lastfm *LastFmAgent, artistName, mbid string) (*artistInfo, error) {
+	key := "artist:" + mbid
+	if mbid == "" {
+		key = "artist:" + artistName
+	}
+
+	if cached, ok := s.cache.GetInfo(key); ok {
+		return cached.(*artistInfo), nil
+	}
+
+	info, err := lastfm.GetArtistInfo(ctx, artistName, mbid)
+	if err != nil {
+		return nil, err
+	}
+	s.cache.SetInfo(key, info)
+	return info, nil
+}
+
+// albumHandler serves GET /{user}/album: album.getInfo for the user's
+// currently playing track.
+func (s *Server) albumHandler(w http.ResponseWriter, r *http.Request, user string) {
+	sourceTrack, lastfm, ok := s.currentTrackForEnrichment(w, r, user)
+	if !ok {
+		return
+	}
+
+	info, err := s.getAlbumInfo(r.Context(), lastfm, sourceTrack.Artist.Text, sourceTrack.Album.Text, sourceTrack.Album.MBID)
+	if err != nil {
+		writeJSON(w, http.StatusBadGateway, map[string]string{"message": "UPSTREAM_ERROR"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]*albumInfo{"album": info})
+}
+
+// artistHandler serves GET /{user}/artist: artist.getInfo for the user's
+// currently playing track.
+func (s *Server) artistHandler(w http.ResponseWriter, r *http.Request, user string) {
+	sourceTrack, lastfm, ok := s.currentTrackForEnrichment(w, r, user)
+	if !ok {
+		return
+	}
+
+	info, err := s.getArtistInfo(r.Context(), lastfm, sourceTrack.Artist.Text, sourceTrack.Artist.MBID)
+	if err != nil {
+		writeJSON(w, http.StatusBadGateway, map[string]string{"message": "UPSTREAM_ERROR"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]*artistInfo{"artist": info})
+}
+
+// currentTrackForEnrichment fetches the user's current track and the
+// Last.fm agent needed to enrich it, writing an error response and
+// returning ok=false if either step fails.
+func (s *Server) currentTrackForEnrichment(w http.ResponseWriter, r *http.Request, user string) (*track, *LastFmAgent, bool) {
+	agent := s.agentForUser(r, user)
+	sourceTrack, freshFor, err := s.serveTrackAndRefresh(r.Context(), user, agent)
+	if err != nil {
+		writeJSON(w, http.StatusBadGateway, map[string]string{"message": "UPSTREAM_ERROR"})
+		return nil, nil, false
+	}
+	writeCacheControl(w, freshFor)
+	if sourceTrack == nil {
+		writeJSON(w, http.StatusOK, map[string]string{"message": "NO_TRACKS_FOUND"})
+		return nil, nil, false
+	}
+
+	lastfm, ok := s.lastFmAgent()
+	if !ok {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"message": "INFO_UNAVAILABLE"})
+		return nil, nil, false
+	}
+
+	return sourceTrack, lastfm, true
+}
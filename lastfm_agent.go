@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+const lastFmBaseURL = "https://ws.audioscrobbler.com/2.0/"
+
+type lastFmResponse struct {
+	RecentTracks recentTracks `json:"recenttracks"`
+}
+
+type recentTracks struct {
+	Track []track `json:"track"`
+}
+
+// LastFmAgent talks to the Last.fm API: fetching recent tracks and, when a
+// shared secret is configured, performing the signed calls needed for the
+// desktop auth flow and scrobble/love submission.
+type LastFmAgent struct {
+	apiKey       string
+	sharedSecret string
+	httpClient   *http.Client
+}
+
+// NewLastFmAgent builds a LastFmAgent using the given API key, shared
+// secret and HTTP client. sharedSecret may be empty for read-only
+// deployments; it is only required by signed write calls.
+func NewLastFmAgent(apiKey, sharedSecret string, httpClient *http.Client) *LastFmAgent {
+	return &LastFmAgent{apiKey: apiKey, sharedSecret: sharedSecret, httpClient: httpClient}
+}
+
+func (a *LastFmAgent) Name() string { return "lastfm" }
+
+func (a *LastFmAgent) GetNowPlaying(ctx context.Context, user string) (*track, error) {
+	params := url.Values{}
+	params.Add("limit", "1")
+	params.Add("user", user)
+
+	var data lastFmResponse
+	if err := a.doAPICall(ctx, "user.getrecenttracks", params, false, false, &data); err != nil {
+		return nil, err
+	}
+
+	if len(data.RecentTracks.Track) == 0 {
+		return nil, nil
+	}
+	return &data.RecentTracks.Track[0], nil
+}
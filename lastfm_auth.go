@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+)
+
+const lastFmAuthURL = "https://www.last.fm/api/auth/"
+
+// GetToken requests an unauthorized request token via auth.getToken, the
+// first step of Last.fm's desktop application auth flow.
+func (a *LastFmAgent) GetToken(ctx context.Context) (string, error) {
+	var data struct {
+		Token string `json:"token"`
+	}
+	if err := a.doAPICall(ctx, "auth.gettoken", nil, true, false, &data); err != nil {
+		return "", err
+	}
+	return data.Token, nil
+}
+
+// AuthURL builds the URL the user must open in a browser to approve token.
+func (a *LastFmAgent) AuthURL(token string) string {
+	params := url.Values{}
+	params.Set("api_key", a.apiKey)
+	params.Set("token", token)
+	return lastFmAuthURL + "?" + params.Encode()
+}
+
+// GetSession exchanges an approved token for a session key via
+// auth.getSession, the last step of the desktop application auth flow.
+func (a *LastFmAgent) GetSession(ctx context.Context, token string) (sessionKey, username string, err error) {
+	params := url.Values{}
+	params.Set("token", token)
+
+	var data struct {
+		Session struct {
+			Name       string `json:"name"`
+			Key        string `json:"key"`
+			Subscriber int    `json:"subscriber"`
+		} `json:"session"`
+	}
+	if err := a.doAPICall(ctx, "auth.getsession", params, true, false, &data); err != nil {
+		return "", "", err
+	}
+	return data.Session.Key, data.Session.Name, nil
+}
+
+// Scrobble submits a played track via track.scrobble, using the given
+// user's session key.
+func (a *LastFmAgent) Scrobble(ctx context.Context, sessionKey, artistName, trackName, albumName string, timestamp int64) error {
+	params := url.Values{}
+	params.Set("artist", artistName)
+	params.Set("track", trackName)
+	if albumName != "" {
+		params.Set("album", albumName)
+	}
+	params.Set("timestamp", strconv.FormatInt(timestamp, 10))
+	params.Set("sk", sessionKey)
+
+	return a.doAPICall(ctx, "track.scrobble", params, true, true, nil)
+}
+
+// SetLoved submits track.love (loved=true) or track.unlove (loved=false)
+// for the given user's session key.
+func (a *LastFmAgent) SetLoved(ctx context.Context, sessionKey, artistName, trackName string, loved bool) error {
+	params := url.Values{}
+	params.Set("artist", artistName)
+	params.Set("track", trackName)
+	params.Set("sk", sessionKey)
+
+	method := "track.love"
+	if !loved {
+		method = "track.unlove"
+	}
+	return a.doAPICall(ctx, method, params, true, true, nil)
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// doAPICall performs a Last.fm API call, decoding the JSON response into
+// out (skipped if out is nil). Set needsSig for methods that require an
+// api_sig per Last.fm's signed-call spec, and usePost for write methods
+// Last.fm expects to receive as a POST body rather than a query string.
+func (a *LastFmAgent) doAPICall(ctx context.Context, method string, params url.Values, needsSig, usePost bool, out interface{}) error {
+	if params == nil {
+		params = url.Values{}
+	}
+	params.Set("method", method)
+	params.Set("api_key", a.apiKey)
+	if needsSig {
+		params.Set("api_sig", sign(params, a.sharedSecret))
+	}
+	params.Set("format", "json")
+
+	var req *http.Request
+	var err error
+	if usePost {
+		req, err = http.NewRequestWithContext(ctx, "POST", lastFmBaseURL, strings.NewReader(params.Encode()))
+		if err == nil {
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+	} else {
+		reqURL, _ := url.Parse(lastFmBaseURL)
+		reqURL.RawQuery = params.Encode()
+		req, err = http.NewRequestWithContext(ctx, "GET", reqURL.String(), nil)
+	}
+	if err != nil {
+		return fmt.Errorf("could not create API request: %w", err)
+	}
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("last.fm API is unreachable: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("received non-200 status code (%d) from Last.fm API", resp.StatusCode)
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("could not parse Last.fm API response: %w", err)
+	}
+	return nil
+}
+
+// sign computes a Last.fm API signature: the md5 of every non-format,
+// non-callback param's key and value concatenated in sorted key order,
+// followed by the shared secret, per Last.fm's authentication spec.
+func sign(params url.Values, secret string) string {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		if k == "format" || k == "callback" {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	for _, k := range keys {
+		sb.WriteString(k)
+		sb.WriteString(params.Get(k))
+	}
+	sb.WriteString(secret)
+
+	sum := md5.Sum([]byte(sb.String()))
+	return hex.EncodeToString(sum[:])
+}
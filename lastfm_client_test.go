@@ -0,0 +1,44 @@
+package main
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestSign(t *testing.T) {
+	params := url.Values{}
+	params.Set("method", "auth.getsession")
+	params.Set("api_key", "key123")
+	params.Set("token", "tok456")
+	params.Set("format", "json")
+
+	got := sign(params, "secret")
+	// md5("api_keykey123methodauth.getsessiontokentok456secret")
+	want := "c663ec102f7d1eb005aeb7af4a66da1d"
+	if got != want {
+		t.Errorf("sign() = %q, want %q", got, want)
+	}
+}
+
+func TestSignIgnoresFormatAndCallback(t *testing.T) {
+	base := url.Values{"method": {"track.scrobble"}, "api_key": {"key"}}
+	withExtras := url.Values{
+		"method":   {"track.scrobble"},
+		"api_key":  {"key"},
+		"format":   {"json"},
+		"callback": {"ignored"},
+	}
+
+	if got, want := sign(withExtras, "secret"), sign(base, "secret"); got != want {
+		t.Errorf("sign() with format/callback = %q, want %q (format/callback must be excluded)", got, want)
+	}
+}
+
+func TestSignIsOrderIndependent(t *testing.T) {
+	a := url.Values{"b": {"2"}, "a": {"1"}}
+	b := url.Values{"a": {"1"}, "b": {"2"}}
+
+	if got, want := sign(a, "secret"), sign(b, "secret"); got != want {
+		t.Errorf("sign() depends on param insertion order: %q != %q", got, want)
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"net/url"
+)
+
+type tagRef struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+type wiki struct {
+	Summary string `json:"summary"`
+	Content string `json:"content"`
+}
+
+type albumInfoResponse struct {
+	Album albumInfo `json:"album"`
+}
+
+// albumInfo mirrors the fields Navidrome's lastfmAgent.callAlbumGetInfo
+// reads out of album.getInfo: enough to show a summary, tags and artwork.
+type albumInfo struct {
+	Name   string  `json:"name"`
+	Artist string  `json:"artist"`
+	MBID   string  `json:"mbid"`
+	URL    string  `json:"url"`
+	Image  []image `json:"image"`
+	Tags   struct {
+		Tag []tagRef `json:"tag"`
+	} `json:"tags"`
+	Wiki wiki `json:"wiki"`
+}
+
+type artistInfoResponse struct {
+	Artist artistInfo `json:"artist"`
+}
+
+type artistInfo struct {
+	Name  string  `json:"name"`
+	MBID  string  `json:"mbid"`
+	URL   string  `json:"url"`
+	Image []image `json:"image"`
+	Tags  struct {
+		Tag []tagRef `json:"tag"`
+	} `json:"tags"`
+	Bio wiki `json:"bio"`
+}
+
+// GetAlbumInfo calls Last.fm's album.getInfo, preferring the MBID when
+// available since it disambiguates re-releases and compilations.
+func (a *LastFmAgent) GetAlbumInfo(ctx context.Context, artistName, albumName, mbid string) (*albumInfo, error) {
+	params := url.Values{}
+	if mbid != "" {
+		params.Add("mbid", mbid)
+	} else {
+		params.Add("artist", artistName)
+		params.Add("album", albumName)
+	}
+
+	var data albumInfoResponse
+	if err := a.doAPICall(ctx, "album.getinfo", params, false, false, &data); err != nil {
+		return nil, err
+	}
+	return &data.Album, nil
+}
+
+// GetArtistInfo calls Last.fm's artist.getInfo, preferring the MBID when
+// available.
+func (a *LastFmAgent) GetArtistInfo(ctx context.Context, artistName, mbid string) (*artistInfo, error) {
+	params := url.Values{}
+	if mbid != "" {
+		params.Add("mbid", mbid)
+	} else {
+		params.Add("artist", artistName)
+	}
+
+	var data artistInfoResponse
+	if err := a.doAPICall(ctx, "artist.getinfo", params, false, false, &data); err != nil {
+		return nil, err
+	}
+	return &data.Artist, nil
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+const listenBrainzBaseURL = "https://api.listenbrainz.org/1/user/"
+
+type listenBrainzResponse struct {
+	Payload struct {
+		Listens []listenBrainzListen `json:"listens"`
+	} `json:"payload"`
+}
+
+type listenBrainzListen struct {
+	ListenedAt    int64                     `json:"listened_at"`
+	PlayingNow    bool                      `json:"playing_now"`
+	TrackMetadata listenBrainzTrackMetadata `json:"track_metadata"`
+}
+
+type listenBrainzTrackMetadata struct {
+	ArtistName  string `json:"artist_name"`
+	ReleaseName string `json:"release_name"`
+	TrackName   string `json:"track_name"`
+	MBIDMapping struct {
+		RecordingMBID string `json:"recording_mbid"`
+	} `json:"mbid_mapping"`
+}
+
+// ListenBrainzAgent fetches recent/now-playing tracks from the ListenBrainz
+// API. ListenBrainz has no equivalent of "now playing" in the listens
+// endpoint, so it is approximated by checking the dedicated playing-now
+// endpoint first and falling back to the most recent listen.
+type ListenBrainzAgent struct {
+	httpClient *http.Client
+}
+
+// NewListenBrainzAgent builds a ListenBrainzAgent using the given HTTP
+// client. ListenBrainz's read API is unauthenticated, so no API key is
+// required.
+func NewListenBrainzAgent(httpClient *http.Client) *ListenBrainzAgent {
+	return &ListenBrainzAgent{httpClient: httpClient}
+}
+
+func (a *ListenBrainzAgent) Name() string { return "listenbrainz" }
+
+func (a *ListenBrainzAgent) GetNowPlaying(ctx context.Context, user string) (*track, error) {
+	listen, nowPlaying, err := a.fetch(ctx, user, "playing-now")
+	if err != nil {
+		return nil, err
+	}
+	if listen == nil {
+		listen, _, err = a.fetch(ctx, user, "listens?count=1")
+		if err != nil {
+			return nil, err
+		}
+		nowPlaying = false
+	}
+	if listen == nil {
+		return nil, nil
+	}
+
+	return listenToTrack(*listen, nowPlaying), nil
+}
+
+func (a *ListenBrainzAgent) fetch(ctx context.Context, user, path string) (*listenBrainzListen, bool, error) {
+	reqURL := listenBrainzBaseURL + user + "/" + path
+
+	req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
+	if err != nil {
+		return nil, false, fmt.Errorf("could not create API request: %w", err)
+	}
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return nil, false, fmt.Errorf("ListenBrainz API is unreachable: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, false, fmt.Errorf("received non-200 status code (%d) from ListenBrainz API", resp.StatusCode)
+	}
+
+	var data listenBrainzResponse
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return nil, false, fmt.Errorf("could not parse ListenBrainz API response: %w", err)
+	}
+
+	if len(data.Payload.Listens) == 0 {
+		return nil, false, nil
+	}
+
+	listen := data.Payload.Listens[0]
+	return &listen, listen.PlayingNow, nil
+}
+
+// listenToTrack normalizes a ListenBrainz listen into the provider-agnostic
+// track struct used across the server.
+func listenToTrack(l listenBrainzListen, nowPlaying bool) *track {
+	t := &track{
+		Artist: artist{Text: l.TrackMetadata.ArtistName},
+		Album:  album{Text: l.TrackMetadata.ReleaseName},
+		Name:   l.TrackMetadata.TrackName,
+		MBID:   l.TrackMetadata.MBIDMapping.RecordingMBID,
+	}
+	if nowPlaying {
+		t.NowPlaying.NowPlaying = "true"
+	} else {
+		t.NowPlaying.NowPlaying = "false"
+	}
+	if l.ListenedAt > 0 {
+		t.Date = &date{UTS: strconv.FormatInt(l.ListenedAt, 10)}
+	}
+	return t
+}
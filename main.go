@@ -6,7 +6,6 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
-	"net/url"
 	"os"
 	"os/signal"
 	"strconv"
@@ -14,21 +13,14 @@ import (
 	"sync"
 	"syscall"
 	"time"
+
+	"golang.org/x/sync/singleflight"
 )
 
 const (
-	lastFmBaseURL  = "https://ws.audioscrobbler.com/2.0/"
 	defaultTimeout = 10 * time.Second
 )
 
-type lastFmResponse struct {
-	RecentTracks recentTracks `json:"recenttracks"`
-}
-
-type recentTracks struct {
-	Track []track `json:"track"`
-}
-
 type track struct {
 	Artist     artist  `json:"artist"`
 	Album      album   `json:"album"`
@@ -83,14 +75,27 @@ type cacheEntry struct {
 	lastFetch time.Time
 }
 
+type infoCacheEntry struct {
+	data      interface{}
+	fetchedAt time.Time
+}
+
+// TrackCache holds both the now-playing cache and the longer-lived
+// album/artist info cache, sharing a single cleanup goroutine between them.
 type TrackCache struct {
 	mu      sync.RWMutex
 	entries map[string]*cacheEntry
+
+	infoMu  sync.RWMutex
+	info    map[string]*infoCacheEntry
+	infoTTL time.Duration
 }
 
-func NewTrackCache() *TrackCache {
+func NewTrackCache(infoTTL time.Duration) *TrackCache {
 	c := &TrackCache{
 		entries: make(map[string]*cacheEntry),
+		info:    make(map[string]*infoCacheEntry),
+		infoTTL: infoTTL,
 	}
 	c.startCleanupRoutine()
 	return c
@@ -107,125 +112,230 @@ func (c *TrackCache) startCleanupRoutine() {
 				}
 			}
 			c.mu.Unlock()
+
+			c.infoMu.Lock()
+			for key, entry := range c.info {
+				if time.Since(entry.fetchedAt) > c.infoTTL {
+					delete(c.info, key)
+				}
+			}
+			c.infoMu.Unlock()
 		}
 	}()
 }
 
+// GetInfo returns a cached album/artist info value, if present and not yet
+// expired.
+func (c *TrackCache) GetInfo(key string) (interface{}, bool) {
+	c.infoMu.RLock()
+	defer c.infoMu.RUnlock()
+
+	entry, found := c.info[key]
+	if !found || time.Since(entry.fetchedAt) > c.infoTTL {
+		return nil, false
+	}
+	return entry.data, true
+}
+
+// SetInfo caches an album/artist info value under key.
+func (c *TrackCache) SetInfo(key string, data interface{}) {
+	c.infoMu.Lock()
+	defer c.infoMu.Unlock()
+	c.info[key] = &infoCacheEntry{data: data, fetchedAt: time.Now()}
+}
+
 type Server struct {
-	apiKey     string
-	httpClient *http.Client
-	cache      *TrackCache
+	cfg          Config
+	httpClient   *http.Client
+	cache        *TrackCache
+	defaultAgent Agent
+	streamHub    *StreamHub
+	store        *Store
+	authFlow     *authFlow
+	sf           singleflight.Group
 }
 
-func NewServer(apiKey string) (*Server, error) {
-	if apiKey == "" {
+func NewServer(cfg Config) (*Server, error) {
+	if cfg.lastFmRequired() && cfg.LastFmAPIKey == "" {
 		return nil, errors.New("LASTFM_API_KEY is not set")
 	}
-	return &Server{
-		apiKey: apiKey,
-		httpClient: &http.Client{
-			Timeout: defaultTimeout,
-		},
-		cache: NewTrackCache(),
-	}, nil
-}
 
-func (s *Server) serveTrackAndRefresh(ctx context.Context, user string) (*track, error) {
-	s.cache.mu.RLock()
-	entry, found := s.cache.entries[user]
-	s.cache.mu.RUnlock()
+	httpClient := &http.Client{
+		Timeout: defaultTimeout,
+	}
+	if cfg.LastFmAPIKey != "" {
+		RegisterAgent(NewLastFmAgent(cfg.LastFmAPIKey, cfg.LastFmSharedSecret, httpClient))
+	}
+	RegisterAgent(NewListenBrainzAgent(httpClient))
 
-	if !found {
-		newTrack, err := s.fetchLatestTrackFromAPI(ctx, user)
+	defaultAgent, ok := getAgent(cfg.DefaultProvider)
+	if !ok {
+		return nil, fmt.Errorf("unknown DEFAULT_PROVIDER %q", cfg.DefaultProvider)
+	}
+
+	var store *Store
+	if cfg.DBPath != "" {
+		var err error
+		store, err = NewStore(cfg.DBPath)
 		if err != nil {
 			return nil, err
 		}
-
-		s.cache.mu.Lock()
-		s.cache.entries[user] = &cacheEntry{
-			track:     newTrack,
-			lastFetch: time.Now(),
-		}
-		s.cache.mu.Unlock()
-		return newTrack, nil
 	}
 
-	s.cache.mu.Lock()
-	if time.Since(entry.lastFetch) > time.Second {
-		entry.lastFetch = time.Now()
-		go s.updateCacheForUser(user)
+	s := &Server{
+		cfg:          cfg,
+		httpClient:   httpClient,
+		cache:        NewTrackCache(cfg.InfoCacheTTL),
+		defaultAgent: defaultAgent,
+		store:        store,
+		authFlow:     newAuthFlow(),
 	}
-	s.cache.mu.Unlock()
-
-	return entry.track, nil
+	// The SSE poller (stream.go) observes now-playing changes independently
+	// of the regular GET /{user} cache path, so it needs its own call into
+	// recordListenIfNew - otherwise a user who only ever opens
+	// /{user}/stream never gets a row written to history.
+	s.streamHub = NewStreamHub(s.recordListenIfNew)
+	return s, nil
 }
 
-func (s *Server) updateCacheForUser(user string) {
-	newTrack, err := s.fetchLatestTrackFromAPI(context.Background(), user)
-	if err != nil {
-		// In case of an error, we keep the old data and log the error.
-		fmt.Fprintf(os.Stderr, "WARN: Failed to update cache for user %s: %v\n", user, err)
-		return
+// agentForUser picks the agent that should serve a request: an explicit
+// ?provider= query parameter wins, then the user's configured default,
+// then the server-wide default provider.
+func (s *Server) agentForUser(r *http.Request, user string) Agent {
+	if name := r.URL.Query().Get("provider"); name != "" {
+		if a, ok := getAgent(name); ok {
+			return a
+		}
+	}
+	if name, ok := s.cfg.UserProviders[user]; ok {
+		if a, ok := getAgent(name); ok {
+			return a
+		}
 	}
+	return s.defaultAgent
+}
+
+// cacheKey namespaces cached tracks by provider so a user switching
+// providers never sees another backend's stale data.
+func cacheKey(user string, a Agent) string {
+	return user + "|" + a.Name()
+}
 
-	s.cache.mu.Lock()
-	defer s.cache.mu.Unlock()
+// serveTrackAndRefresh implements a stale-while-revalidate policy: a cache
+// entry younger than MaxStale is served immediately (kicking off a
+// background refresh once it's older than SwrThreshold), and only a
+// missing or fully stale entry blocks the caller on an upstream fetch.
+// Concurrent fetches for the same user+provider - whether blocking or
+// backgrounded - are coalesced through singleflight so a cold cache never
+// causes a thundering herd against the upstream API. It returns the track
+// together with how long the response may be treated as fresh, for the
+// caller to turn into a Cache-Control header.
+func (s *Server) serveTrackAndRefresh(ctx context.Context, user string, a Agent) (*track, time.Duration, error) {
+	key := cacheKey(user, a)
+
+	// entry, once stored, is never mutated in place (see fetchAndCache) -
+	// only the map slot is swapped for a new *cacheEntry - so it's safe to
+	// read entry.track/entry.lastFetch after releasing the lock.
+	s.cache.mu.RLock()
+	entry, found := s.cache.entries[key]
+	s.cache.mu.RUnlock()
 
-	if entry, found := s.cache.entries[user]; found {
-		entry.track = newTrack
+	if found {
+		age := time.Since(entry.lastFetch)
+		if age < s.cfg.MaxStale {
+			if age >= s.cfg.SwrThreshold {
+				go s.refreshInBackground(user, a, key)
+			}
+			return entry.track, s.cfg.MaxStale - age, nil
+		}
 	}
-}
 
-func (s *Server) fetchLatestTrackFromAPI(ctx context.Context, user string) (*track, error) {
-	baseURL, _ := url.Parse(lastFmBaseURL)
-	params := url.Values{}
-	params.Add("method", "user.getrecenttracks")
-	params.Add("limit", "1")
-	params.Add("format", "json")
-	params.Add("user", user)
-	params.Add("api_key", s.apiKey)
-	baseURL.RawQuery = params.Encode()
-
-	req, err := http.NewRequestWithContext(ctx, "GET", baseURL.String(), nil)
+	newTrack, err := s.fetchAndCache(ctx, user, a, key)
 	if err != nil {
-		return nil, fmt.Errorf("could not create API request: %w", err)
+		return nil, 0, err
 	}
+	return newTrack, s.cfg.MaxStale, nil
+}
+
+// fetchAndCache calls the agent through singleflight, so multiple callers
+// racing on a cold or fully stale entry share a single upstream request,
+// and stores the result.
+func (s *Server) fetchAndCache(ctx context.Context, user string, a Agent, key string) (*track, error) {
+	v, err, _ := s.sf.Do(key, func() (interface{}, error) {
+		newTrack, err := a.GetNowPlaying(ctx, user)
+		if err != nil {
+			return nil, err
+		}
 
-	resp, err := s.httpClient.Do(req)
+		s.cache.mu.Lock()
+		previousEntry, found := s.cache.entries[key]
+		var previous *track
+		if found {
+			previous = previousEntry.track
+		}
+		s.cache.entries[key] = &cacheEntry{track: newTrack, lastFetch: time.Now()}
+		s.cache.mu.Unlock()
+
+		s.recordListenIfNew(user, previous, newTrack)
+		return newTrack, nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("last.fm API is unreachable: %w", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
+	return v.(*track), nil
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("received non-200 status code (%d) from Last.fm API", resp.StatusCode)
+// refreshInBackground revalidates a still-fresh-enough cache entry without
+// blocking the caller that triggered it. It shares the same singleflight
+// key as fetchAndCache, so a background refresh and a blocking fetch for
+// the same user never run concurrently.
+func (s *Server) refreshInBackground(user string, a Agent, key string) {
+	if _, err := s.fetchAndCache(context.Background(), user, a, key); err != nil {
+		fmt.Fprintf(os.Stderr, "WARN: Failed to update cache for user %s: %v\n", user, err)
 	}
+}
 
-	var lastFmData lastFmResponse
-	if err := json.NewDecoder(resp.Body).Decode(&lastFmData); err != nil {
-		return nil, fmt.Errorf("could not parse Last.fm API response: %w", err)
+// recordListenIfNew appends newTrack to the listen history store if it
+// represents a freshly scrobbled track: it must carry a Date and differ
+// from whatever was previously cached for this user.
+func (s *Server) recordListenIfNew(user string, previous, current *track) {
+	if s.store == nil || current == nil || current.Date == nil || current.Date.UTS == "" {
+		return
+	}
+	if previous != nil && previous.Date != nil && previous.Date.UTS == current.Date.UTS {
+		return
 	}
 
-	if len(lastFmData.RecentTracks.Track) == 0 {
-		return nil, nil
+	uts, err := strconv.ParseInt(current.Date.UTS, 10, 64)
+	if err != nil {
+		return
 	}
 
-	return &lastFmData.RecentTracks.Track[0], nil
-}
+	listen := Listen{
+		User:   user,
+		Artist: current.Artist.Text,
+		Album:  current.Album.Text,
+		Title:  current.Name,
+		MBID:   current.MBID,
+		UTS:    uts,
+		URL:    current.URL,
+	}
 
-func (s *Server) latestSongHandler(w http.ResponseWriter, r *http.Request) {
-	user := strings.Trim(r.URL.Path, "/")
-	if user == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"message": "BAD_REQUEST"})
-		return
+	if err := s.store.AppendListen(listen); err != nil {
+		fmt.Fprintf(os.Stderr, "WARN: Failed to record listen for user %s: %v\n", user, err)
 	}
+}
 
-	sourceTrack, err := s.serveTrackAndRefresh(r.Context(), user)
+func (s *Server) latestSongHandler(w http.ResponseWriter, r *http.Request, user string) {
+	agent := s.agentForUser(r, user)
+	sourceTrack, freshFor, err := s.serveTrackAndRefresh(r.Context(), user, agent)
 	if err != nil {
 		writeJSON(w, http.StatusBadGateway, map[string]string{"message": "UPSTREAM_ERROR"})
 		return
 	}
 
+	writeCacheControl(w, freshFor)
+
 	if sourceTrack == nil {
 		writeJSON(w, http.StatusOK, map[string]string{"message": "NO_TRACKS_FOUND"})
 		return
@@ -244,6 +354,43 @@ func (s *Server) latestSongHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	payload := trackPayload(sourceTrack)
+	s.addRequestedEnrichment(r, payload, sourceTrack)
+	writeJSON(w, http.StatusOK, payload)
+}
+
+// addRequestedEnrichment adds album/artist info to payload for each value
+// named in ?include= (e.g. "?include=album,artist"), best-effort: a failed
+// lookup is silently omitted rather than failing the whole request.
+func (s *Server) addRequestedEnrichment(r *http.Request, payload map[string]interface{}, sourceTrack *track) {
+	include := r.URL.Query().Get("include")
+	if include == "" {
+		return
+	}
+
+	lastfm, ok := s.lastFmAgent()
+	if !ok {
+		return
+	}
+
+	for _, field := range strings.Split(include, ",") {
+		switch strings.TrimSpace(field) {
+		case "album":
+			if info, err := s.getAlbumInfo(r.Context(), lastfm, sourceTrack.Artist.Text, sourceTrack.Album.Text, sourceTrack.Album.MBID); err == nil {
+				payload["album"] = info
+			}
+		case "artist":
+			if info, err := s.getArtistInfo(r.Context(), lastfm, sourceTrack.Artist.Text, sourceTrack.Artist.MBID); err == nil {
+				payload["artist"] = info
+			}
+		}
+	}
+}
+
+// trackPayload builds the JSON-able representation of a track shared by the
+// main endpoint and the SSE stream: the original 'date' object is replaced
+// with a numeric 'date_uts' field.
+func trackPayload(sourceTrack *track) map[string]interface{} {
 	outputTrack := trackWithDateUTS{
 		track: *sourceTrack,
 	}
@@ -259,7 +406,17 @@ func (s *Server) latestSongHandler(w http.ResponseWriter, r *http.Request) {
 	// will cause it to be excluded from the final JSON.
 	outputTrack.Date = nil
 
-	writeJSON(w, http.StatusOK, map[string]trackWithDateUTS{"track": outputTrack})
+	return map[string]interface{}{"track": outputTrack}
+}
+
+// writeCacheControl sets a max-age reflecting how much longer the served
+// entry may be treated as fresh, so downstream CDNs cache it accordingly.
+func writeCacheControl(w http.ResponseWriter, freshFor time.Duration) {
+	seconds := int(freshFor / time.Second)
+	if seconds < 0 {
+		seconds = 0
+	}
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", seconds))
 }
 
 func writeJSON(w http.ResponseWriter, statusCode int, data interface{}) {
@@ -271,7 +428,7 @@ func writeJSON(w http.ResponseWriter, statusCode int, data interface{}) {
 func corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
 		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
 
 		if r.Method == http.MethodOptions {
@@ -283,7 +440,7 @@ func corsMiddleware(next http.Handler) http.Handler {
 }
 
 func main() {
-	server, err := NewServer(os.Getenv("LASTFM_API_KEY"))
+	server, err := NewServer(loadConfig())
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "FATAL: Failed to create server: %v\n", err)
 		os.Exit(1)
@@ -295,7 +452,7 @@ func main() {
 	}
 
 	mux := http.NewServeMux()
-	mux.HandleFunc("/", server.latestSongHandler)
+	mux.HandleFunc("/", server.routeHandler)
 
 	httpServer := &http.Server{
 		Addr:         ":" + port,
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingAgent returns an incrementing track on every call and counts how
+// many times the upstream was actually hit.
+type countingAgent struct {
+	calls int32
+	delay time.Duration
+}
+
+func (a *countingAgent) Name() string { return "counting" }
+
+func (a *countingAgent) GetNowPlaying(ctx context.Context, user string) (*track, error) {
+	n := atomic.AddInt32(&a.calls, 1)
+	if a.delay > 0 {
+		time.Sleep(a.delay)
+	}
+	return &track{Name: user, MBID: string(rune('a' + n))}, nil
+}
+
+func newTestServer(a Agent) *Server {
+	return &Server{
+		cache:        NewTrackCache(time.Hour),
+		defaultAgent: a,
+		cfg:          Config{MaxStale: 50 * time.Millisecond, SwrThreshold: 10 * time.Millisecond},
+	}
+}
+
+func TestServeTrackAndRefreshCoalescesConcurrentFetches(t *testing.T) {
+	a := &countingAgent{delay: 20 * time.Millisecond}
+	s := newTestServer(a)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, _, err := s.serveTrackAndRefresh(context.Background(), "alice", a); err != nil {
+				t.Errorf("serveTrackAndRefresh() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := atomic.LoadInt32(&a.calls); got != 1 {
+		t.Errorf("upstream calls = %d, want 1 (concurrent cold fetches should coalesce)", got)
+	}
+}
+
+func TestServeTrackAndRefreshServesStaleWhileRevalidating(t *testing.T) {
+	a := &countingAgent{}
+	s := newTestServer(a)
+
+	if _, _, err := s.serveTrackAndRefresh(context.Background(), "alice", a); err != nil {
+		t.Fatalf("initial serveTrackAndRefresh() error = %v", err)
+	}
+	if got := atomic.LoadInt32(&a.calls); got != 1 {
+		t.Fatalf("upstream calls after first fetch = %d, want 1", got)
+	}
+
+	// Past SwrThreshold but still under MaxStale: should serve the cached
+	// value immediately and kick off a background refresh.
+	time.Sleep(20 * time.Millisecond)
+	trk, _, err := s.serveTrackAndRefresh(context.Background(), "alice", a)
+	if err != nil {
+		t.Fatalf("second serveTrackAndRefresh() error = %v", err)
+	}
+	if trk == nil {
+		t.Fatal("second serveTrackAndRefresh() returned nil track")
+	}
+
+	if got := atomic.LoadInt32(&a.calls); got != 1 {
+		t.Errorf("upstream calls immediately after stale read = %d, want still 1 (refresh is backgrounded)", got)
+	}
+
+	// Let the background refresh land.
+	time.Sleep(40 * time.Millisecond)
+	if got := atomic.LoadInt32(&a.calls); got != 2 {
+		t.Errorf("upstream calls after background refresh = %d, want 2", got)
+	}
+}
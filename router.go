@@ -0,0 +1,77 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+)
+
+// routeHandler dispatches requests of the form /{user}, /{user}/{action} or
+// /auth/lastfm/{step} to the appropriate handler. It is intentionally a
+// thin hand-rolled router rather than pulling in a mux library, matching
+// the rest of the server.
+func (s *Server) routeHandler(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(r.URL.Path, "/")
+	if path == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"message": "BAD_REQUEST"})
+		return
+	}
+
+	segments := strings.Split(path, "/")
+
+	if segments[0] == "auth" {
+		s.authRouteHandler(w, r, segments[1:])
+		return
+	}
+
+	user := segments[0]
+
+	if len(segments) == 1 {
+		s.latestSongHandler(w, r, user)
+		return
+	}
+
+	switch segments[1] {
+	case "stream":
+		s.streamHandler(w, r, user)
+	case "history":
+		s.historyHandler(w, r, user)
+	case "album":
+		s.albumHandler(w, r, user)
+	case "artist":
+		s.artistHandler(w, r, user)
+	case "scrobble":
+		requireMethod(w, r, http.MethodPost, func() { s.scrobbleHandler(w, r, user) })
+	case "love":
+		requireMethod(w, r, http.MethodPost, func() { s.loveHandler(w, r, user) })
+	case "unlove":
+		requireMethod(w, r, http.MethodPost, func() { s.unloveHandler(w, r, user) })
+	default:
+		writeJSON(w, http.StatusNotFound, map[string]string{"message": "NOT_FOUND"})
+	}
+}
+
+func (s *Server) authRouteHandler(w http.ResponseWriter, r *http.Request, rest []string) {
+	if len(rest) != 2 || rest[0] != "lastfm" {
+		writeJSON(w, http.StatusNotFound, map[string]string{"message": "NOT_FOUND"})
+		return
+	}
+
+	switch rest[1] {
+	case "start":
+		s.authLastFmStartHandler(w, r)
+	case "callback":
+		s.authLastFmCallbackHandler(w, r)
+	default:
+		writeJSON(w, http.StatusNotFound, map[string]string{"message": "NOT_FOUND"})
+	}
+}
+
+// requireMethod runs next if r was made with method, otherwise it responds
+// with 405 Method Not Allowed.
+func requireMethod(w http.ResponseWriter, r *http.Request, method string, next func()) {
+	if r.Method != method {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"message": "METHOD_NOT_ALLOWED"})
+		return
+	}
+	next()
+}
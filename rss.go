@@ -0,0 +1,54 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title       string    `xml:"title"`
+	Description string    `xml:"description"`
+	Link        string    `xml:"link"`
+	Items       []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title   string `xml:"title"`
+	Link    string `xml:"link"`
+	GUID    string `xml:"guid"`
+	PubDate string `xml:"pubDate"`
+}
+
+// writeRSS renders a user's listen history as an RSS 2.0 feed so it can be
+// wired into feed readers.
+func writeRSS(w http.ResponseWriter, user string, listens []Listen) {
+	feed := rssFeed{
+		Version: "2.0",
+		Channel: rssChannel{
+			Title:       fmt.Sprintf("%s's scrobbles", user),
+			Description: fmt.Sprintf("Recent scrobbles for %s", user),
+			Link:        "https://www.last.fm/user/" + user,
+		},
+	}
+
+	for _, l := range listens {
+		feed.Channel.Items = append(feed.Channel.Items, rssItem{
+			Title:   fmt.Sprintf("%s - %s", l.Artist, l.Title),
+			Link:    l.URL,
+			GUID:    fmt.Sprintf("%s-%d", user, l.UTS),
+			PubDate: time.Unix(l.UTS, 0).UTC().Format(time.RFC1123Z),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/rss+xml; charset=utf-8")
+	w.Write([]byte(xml.Header))
+	_ = xml.NewEncoder(w).Encode(feed)
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type scrobbleRequest struct {
+	Artist    string `json:"artist"`
+	Track     string `json:"track"`
+	Album     string `json:"album"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+type loveRequest struct {
+	Artist string `json:"artist"`
+	Track  string `json:"track"`
+}
+
+// scrobbleHandler serves POST /{user}/scrobble.
+func (s *Server) scrobbleHandler(w http.ResponseWriter, r *http.Request, user string) {
+	sessionKey, lastfm, ok := s.authenticatedLastFm(w, r, user)
+	if !ok {
+		return
+	}
+
+	var req scrobbleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Artist == "" || req.Track == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"message": "BAD_REQUEST"})
+		return
+	}
+	if req.Timestamp == 0 {
+		req.Timestamp = time.Now().Unix()
+	}
+
+	if err := lastfm.Scrobble(r.Context(), sessionKey, req.Artist, req.Track, req.Album, req.Timestamp); err != nil {
+		writeJSON(w, http.StatusBadGateway, map[string]string{"message": "UPSTREAM_ERROR"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"message": "SCROBBLED"})
+}
+
+// loveHandler serves POST /{user}/love.
+func (s *Server) loveHandler(w http.ResponseWriter, r *http.Request, user string) {
+	s.setLovedHandler(w, r, user, true)
+}
+
+// unloveHandler serves POST /{user}/unlove.
+func (s *Server) unloveHandler(w http.ResponseWriter, r *http.Request, user string) {
+	s.setLovedHandler(w, r, user, false)
+}
+
+func (s *Server) setLovedHandler(w http.ResponseWriter, r *http.Request, user string, loved bool) {
+	sessionKey, lastfm, ok := s.authenticatedLastFm(w, r, user)
+	if !ok {
+		return
+	}
+
+	var req loveRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Artist == "" || req.Track == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"message": "BAD_REQUEST"})
+		return
+	}
+
+	if err := lastfm.SetLoved(r.Context(), sessionKey, req.Artist, req.Track, loved); err != nil {
+		writeJSON(w, http.StatusBadGateway, map[string]string{"message": "UPSTREAM_ERROR"})
+		return
+	}
+
+	message := "LOVED"
+	if !loved {
+		message = "UNLOVED"
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"message": message})
+}
+
+// authenticatedLastFm resolves the Last.fm agent and the user's persisted
+// session key, writing an error response and returning ok=false if either
+// is unavailable or the caller doesn't present the per-user write token
+// minted for user during the auth flow. Without this, the user path
+// segment alone would let anyone scrobble/love/unlove on another user's
+// behalf.
+func (s *Server) authenticatedLastFm(w http.ResponseWriter, r *http.Request, user string) (string, *LastFmAgent, bool) {
+	if s.store == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"message": "HISTORY_DISABLED"})
+		return "", nil, false
+	}
+
+	lastfm, ok := s.lastFmAgent()
+	if !ok || s.cfg.LastFmSharedSecret == "" {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"message": "LASTFM_AUTH_DISABLED"})
+		return "", nil, false
+	}
+
+	sessionKey, apiTokenHash, found, err := s.store.LastFmSession(user)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"message": "STORE_ERROR"})
+		return "", nil, false
+	}
+	if !found || !validAPIToken(r, apiTokenHash) {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"message": "NOT_AUTHENTICATED"})
+		return "", nil, false
+	}
+
+	return sessionKey, lastfm, true
+}
+
+// validAPIToken reports whether r carries the bearer token whose sha256
+// hash matches storedHash, the per-user write token returned once from
+// /auth/lastfm/callback.
+func validAPIToken(r *http.Request, storedHash string) bool {
+	token := bearerToken(r)
+	if token == "" || storedHash == "" {
+		return false
+	}
+	sum := sha256.Sum256([]byte(token))
+	got := hex.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(got), []byte(storedHash)) == 1
+}
+
+// bearerToken extracts the token from an "Authorization: Bearer <token>"
+// header, or "" if absent/malformed.
+func bearerToken(r *http.Request) string {
+	const prefix = "Bearer "
+	h := r.Header.Get("Authorization")
+	if !strings.HasPrefix(h, prefix) {
+		return ""
+	}
+	return strings.TrimPrefix(h, prefix)
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestValidAPIToken(t *testing.T) {
+	storedHash := hashToken("correct-token")
+
+	cases := []struct {
+		name   string
+		header string
+		want   bool
+	}{
+		{"correct token", "Bearer correct-token", true},
+		{"wrong token", "Bearer wrong-token", false},
+		{"missing header", "", false},
+		{"missing bearer prefix", "correct-token", false},
+	}
+
+	for _, c := range cases {
+		r := httptest.NewRequest(http.MethodPost, "/alice/scrobble", nil)
+		if c.header != "" {
+			r.Header.Set("Authorization", c.header)
+		}
+		if got := validAPIToken(r, storedHash); got != c.want {
+			t.Errorf("%s: validAPIToken() = %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestValidAPITokenRejectsEmptyStoredHash(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/alice/scrobble", nil)
+	r.Header.Set("Authorization", "Bearer anything")
+	if validAPIToken(r, "") {
+		t.Error("validAPIToken() with empty stored hash = true, want false")
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+
+	_ "modernc.org/sqlite"
+)
+
+// Listen is a single persisted scrobble, as recorded in the listens table.
+type Listen struct {
+	User   string `json:"user"`
+	Artist string `json:"artist"`
+	Album  string `json:"album"`
+	Title  string `json:"title"`
+	MBID   string `json:"mbid"`
+	UTS    int64  `json:"uts"`
+	URL    string `json:"url"`
+}
+
+// Store persists per-user listen history to SQLite.
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore opens (creating if necessary) the SQLite database at path and
+// migrates the schema.
+func NewStore(path string) (*Store, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("could not open history database: %w", err)
+	}
+
+	s := &Store{db: db}
+	if err := s.migrate(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Store) migrate() error {
+	const schema = `
+	CREATE TABLE IF NOT EXISTS listens (
+		user   TEXT NOT NULL,
+		artist TEXT NOT NULL,
+		album  TEXT NOT NULL,
+		title  TEXT NOT NULL,
+		mbid   TEXT NOT NULL,
+		uts    INTEGER NOT NULL,
+		url    TEXT NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS idx_listens_user_uts ON listens(user, uts DESC);
+
+	CREATE TABLE IF NOT EXISTS lastfm_sessions (
+		user            TEXT PRIMARY KEY,
+		session_key     TEXT NOT NULL,
+		lastfm_username TEXT NOT NULL,
+		api_token_hash  TEXT NOT NULL DEFAULT ''
+	);
+	`
+	_, err := s.db.Exec(schema)
+	return err
+}
+
+// SaveLastFmSession persists the session key obtained for user via the
+// Last.fm desktop auth flow, overwriting any previous session. apiTokenHash
+// is the sha256 hex digest of the per-user write token minted alongside it
+// (see authLastFmCallbackHandler); only the hash is ever stored.
+func (s *Store) SaveLastFmSession(user, sessionKey, lastfmUsername, apiTokenHash string) error {
+	const q = `INSERT INTO lastfm_sessions (user, session_key, lastfm_username, api_token_hash) VALUES (?, ?, ?, ?)
+		ON CONFLICT(user) DO UPDATE SET session_key = excluded.session_key, lastfm_username = excluded.lastfm_username, api_token_hash = excluded.api_token_hash`
+	_, err := s.db.Exec(q, user, sessionKey, lastfmUsername, apiTokenHash)
+	return err
+}
+
+// LastFmSession returns the persisted Last.fm session key and write-token
+// hash for user, if any.
+func (s *Store) LastFmSession(user string) (sessionKey, apiTokenHash string, found bool, err error) {
+	const q = `SELECT session_key, api_token_hash FROM lastfm_sessions WHERE user = ?`
+	err = s.db.QueryRow(q, user).Scan(&sessionKey, &apiTokenHash)
+	if err == sql.ErrNoRows {
+		return "", "", false, nil
+	}
+	if err != nil {
+		return "", "", false, fmt.Errorf("could not query Last.fm session: %w", err)
+	}
+	return sessionKey, apiTokenHash, true, nil
+}
+
+// AppendListen records a newly observed scrobble.
+func (s *Store) AppendListen(l Listen) error {
+	const q = `INSERT INTO listens (user, artist, album, title, mbid, uts, url) VALUES (?, ?, ?, ?, ?, ?, ?)`
+	_, err := s.db.Exec(q, l.User, l.Artist, l.Album, l.Title, l.MBID, l.UTS, l.URL)
+	return err
+}
+
+// RecentListens returns up to limit listens for user, newest first,
+// restricted to those at or after sinceUTS (0 means no lower bound).
+func (s *Store) RecentListens(user string, limit int, sinceUTS int64) ([]Listen, error) {
+	const q = `SELECT user, artist, album, title, mbid, uts, url FROM listens WHERE user = ? AND uts >= ? ORDER BY uts DESC LIMIT ?`
+	rows, err := s.db.Query(q, user, sinceUTS, limit)
+	if err != nil {
+		return nil, fmt.Errorf("could not query listen history: %w", err)
+	}
+	defer rows.Close()
+
+	var listens []Listen
+	for rows.Next() {
+		var l Listen
+		if err := rows.Scan(&l.User, &l.Artist, &l.Album, &l.Title, &l.MBID, &l.UTS, &l.URL); err != nil {
+			return nil, fmt.Errorf("could not scan listen row: %w", err)
+		}
+		listens = append(listens, l)
+	}
+	return listens, rows.Err()
+}
+
+// Close closes the underlying database connection.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
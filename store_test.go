@@ -0,0 +1,74 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	s, err := NewStore(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewStore() error = %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestStoreAppendAndRecentListens(t *testing.T) {
+	s := newTestStore(t)
+
+	listens := []Listen{
+		{User: "alice", Artist: "A", Title: "One", UTS: 100},
+		{User: "alice", Artist: "A", Title: "Two", UTS: 200},
+		{User: "bob", Artist: "B", Title: "Other", UTS: 150},
+	}
+	for _, l := range listens {
+		if err := s.AppendListen(l); err != nil {
+			t.Fatalf("AppendListen() error = %v", err)
+		}
+	}
+
+	got, err := s.RecentListens("alice", 10, 0)
+	if err != nil {
+		t.Fatalf("RecentListens() error = %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("RecentListens() returned %d listens, want 2", len(got))
+	}
+	if got[0].Title != "Two" || got[1].Title != "One" {
+		t.Errorf("RecentListens() = %+v, want newest first", got)
+	}
+
+	got, err = s.RecentListens("alice", 10, 150)
+	if err != nil {
+		t.Fatalf("RecentListens() error = %v", err)
+	}
+	if len(got) != 1 || got[0].Title != "Two" {
+		t.Errorf("RecentListens() with sinceUTS=150 = %+v, want only Two", got)
+	}
+}
+
+func TestStoreLastFmSession(t *testing.T) {
+	s := newTestStore(t)
+
+	if _, _, found, err := s.LastFmSession("alice"); err != nil || found {
+		t.Fatalf("LastFmSession() before save = (found=%v, err=%v), want not found", found, err)
+	}
+
+	if err := s.SaveLastFmSession("alice", "sk-1", "alice_lfm", "hash-1"); err != nil {
+		t.Fatalf("SaveLastFmSession() error = %v", err)
+	}
+	key, hash, found, err := s.LastFmSession("alice")
+	if err != nil || !found || key != "sk-1" || hash != "hash-1" {
+		t.Fatalf("LastFmSession() = (%q, %q, %v, %v), want (sk-1, hash-1, true, nil)", key, hash, found, err)
+	}
+
+	if err := s.SaveLastFmSession("alice", "sk-2", "alice_lfm", "hash-2"); err != nil {
+		t.Fatalf("SaveLastFmSession() overwrite error = %v", err)
+	}
+	key, hash, found, err = s.LastFmSession("alice")
+	if err != nil || !found || key != "sk-2" || hash != "hash-2" {
+		t.Fatalf("LastFmSession() after overwrite = (%q, %q, %v, %v), want (sk-2, hash-2, true, nil)", key, hash, found, err)
+	}
+}
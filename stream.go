@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	streamActiveInterval  = 10 * time.Second
+	streamIdleInterval    = 60 * time.Second
+	streamMaxIdleInterval = 120 * time.Second
+)
+
+// trackBroadcaster polls an Agent for one user on an adaptive interval and
+// fans out track changes to any number of subscribers. It is started on the
+// first subscriber and torn down once the last one unsubscribes, similar to
+// how the BBC metadata poller's dynamic polling_timeout backs off when
+// nothing is happening.
+type trackBroadcaster struct {
+	mu          sync.Mutex
+	subscribers map[chan *track]struct{}
+	cancel      context.CancelFunc
+}
+
+// StreamHub manages one trackBroadcaster per user+provider pair.
+type StreamHub struct {
+	mu           sync.Mutex
+	broadcasters map[string]*trackBroadcaster
+	onChange     func(user string, previous, current *track)
+}
+
+// NewStreamHub creates an empty StreamHub. onChange, if non-nil, is called
+// with a user's previous and current track every time the poller observes a
+// change - regardless of whether any SSE client is currently subscribed -
+// so a user who only ever opens /{user}/stream still gets their listens
+// recorded.
+func NewStreamHub(onChange func(user string, previous, current *track)) *StreamHub {
+	return &StreamHub{broadcasters: make(map[string]*trackBroadcaster), onChange: onChange}
+}
+
+// Subscribe registers a new listener for a user's track changes, starting
+// the polling goroutine if this is the first subscriber for that user and
+// provider. The returned unsubscribe function must be called exactly once,
+// typically via defer.
+func (h *StreamHub) Subscribe(user string, a Agent) (<-chan *track, func()) {
+	key := cacheKey(user, a)
+	ch := make(chan *track, 1)
+
+	h.mu.Lock()
+	b, found := h.broadcasters[key]
+	if !found {
+		ctx, cancel := context.WithCancel(context.Background())
+		b = &trackBroadcaster{
+			subscribers: make(map[chan *track]struct{}),
+			cancel:      cancel,
+		}
+		h.broadcasters[key] = b
+		go b.poll(ctx, user, a, h.onChange)
+	}
+	b.mu.Lock()
+	b.subscribers[ch] = struct{}{}
+	b.mu.Unlock()
+	h.mu.Unlock()
+
+	unsubscribe := func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+
+		b.mu.Lock()
+		delete(b.subscribers, ch)
+		empty := len(b.subscribers) == 0
+		b.mu.Unlock()
+
+		if empty {
+			b.cancel()
+			delete(h.broadcasters, key)
+		}
+	}
+
+	return ch, unsubscribe
+}
+
+func (b *trackBroadcaster) poll(ctx context.Context, user string, a Agent, onChange func(user string, previous, current *track)) {
+	interval := streamActiveInterval
+	var last *track
+
+	for {
+		t, err := a.GetNowPlaying(ctx, user)
+		if err == nil {
+			if trackChanged(last, t) {
+				previous := last
+				last = t
+				b.broadcast(t)
+				if onChange != nil {
+					onChange(user, previous, t)
+				}
+			}
+			if t != nil && t.NowPlaying.NowPlaying == "true" {
+				interval = streamActiveInterval
+			} else {
+				interval = nextIdleInterval(interval)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+func (b *trackBroadcaster) broadcast(t *track) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subscribers {
+		select {
+		case ch <- t:
+		default:
+			// Subscriber hasn't drained the previous event yet; drop this
+			// one rather than block the poller.
+		}
+	}
+}
+
+// nextIdleInterval backs off the polling interval while nothing is playing,
+// capped at streamMaxIdleInterval.
+func nextIdleInterval(current time.Duration) time.Duration {
+	if current < streamIdleInterval {
+		return streamIdleInterval
+	}
+	next := current * 2
+	if next > streamMaxIdleInterval {
+		return streamMaxIdleInterval
+	}
+	return next
+}
+
+// trackChanged reports whether next represents a different track than prev,
+// comparing MBID, name and artist so repeated now-playing polls for the
+// same song don't spam subscribers.
+func trackChanged(prev, next *track) bool {
+	if (prev == nil) != (next == nil) {
+		return true
+	}
+	if prev == nil {
+		return false
+	}
+	return prev.MBID != next.MBID || prev.Name != next.Name || prev.Artist.Text != next.Artist.Text
+}
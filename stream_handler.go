@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// streamHandler serves Server-Sent Events for a user's now-playing track,
+// pushing a new event only when the track actually changes.
+func (s *Server) streamHandler(w http.ResponseWriter, r *http.Request, user string) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"message": "STREAMING_UNSUPPORTED"})
+		return
+	}
+
+	// httpServer.WriteTimeout is sized for ordinary request/response
+	// handlers and is fixed at header-read time; it isn't reset by later
+	// writes, so a long-lived SSE connection would otherwise be cut mid
+	// stream. Lift the write deadline for the life of this connection.
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"message": "STREAMING_UNSUPPORTED"})
+		return
+	}
+
+	agent := s.agentForUser(r, user)
+	updates, unsubscribe := s.streamHub.Subscribe(user, agent)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case t := <-updates:
+			if err := writeSSEEvent(w, t); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+func writeSSEEvent(w http.ResponseWriter, t *track) error {
+	var payload interface{} = map[string]string{"message": "NO_TRACKS_FOUND"}
+	if t != nil {
+		payload = trackPayload(t)
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "data: %s\n\n", data)
+	return err
+}
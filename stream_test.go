@@ -0,0 +1,49 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTrackChanged(t *testing.T) {
+	a := &track{Name: "Song A", Artist: artist{Text: "Artist"}, MBID: "mbid-a"}
+	b := &track{Name: "Song B", Artist: artist{Text: "Artist"}, MBID: "mbid-b"}
+	aAgain := &track{Name: "Song A", Artist: artist{Text: "Artist"}, MBID: "mbid-a"}
+
+	cases := []struct {
+		name string
+		prev *track
+		next *track
+		want bool
+	}{
+		{"nil to nil", nil, nil, false},
+		{"nil to track", nil, a, true},
+		{"track to nil", a, nil, true},
+		{"same track", a, aAgain, false},
+		{"different track", a, b, true},
+	}
+
+	for _, c := range cases {
+		if got := trackChanged(c.prev, c.next); got != c.want {
+			t.Errorf("%s: trackChanged() = %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestNextIdleInterval(t *testing.T) {
+	cases := []struct {
+		current time.Duration
+		want    time.Duration
+	}{
+		{streamActiveInterval, streamIdleInterval},
+		{streamIdleInterval, streamIdleInterval * 2},
+		{streamMaxIdleInterval, streamMaxIdleInterval},
+		{streamMaxIdleInterval / 2, streamMaxIdleInterval},
+	}
+
+	for _, c := range cases {
+		if got := nextIdleInterval(c.current); got != c.want {
+			t.Errorf("nextIdleInterval(%v) = %v, want %v", c.current, got, c.want)
+		}
+	}
+}